@@ -2,6 +2,8 @@ package main
 
 import (
     "context"
+    "flag"
+    "fmt"
     "log"
     "net/http"
     "os"
@@ -13,7 +15,31 @@ import (
 )
 
 func main() {
-    s := store.NewFileStore("./data")
+    if len(os.Args) > 1 && os.Args[1] == "presign" {
+        runPresign(os.Args[2:])
+        return
+    }
+
+    backendFlag := flag.String("backend", envOrDefault("BACKEND", "fs"), "storage backend: fs or s3")
+    s3EndpointFlag := flag.String("s3-endpoint", os.Getenv("S3_ENDPOINT"), "S3-compatible endpoint URL (empty for AWS S3)")
+    keysFlag := flag.String("keys", os.Getenv("KEYS_FILE"), "path to a JSON file of {keyId: secret} signing keys (empty disables auth)")
+    flag.Parse()
+
+    s, err := newFileStore(*backendFlag, *s3EndpointFlag)
+    if err != nil {
+        log.Fatalf("failed to initialize %s backend: %s", *backendFlag, err)
+    }
+
+    if *keysFlag != "" {
+        keys, err := store.LoadKeysFile(*keysFlag)
+        if err != nil {
+            log.Fatalf("failed to load keys file %s: %s", *keysFlag, err)
+        }
+        s.Keys = keys
+    }
+
+    janitorStop := make(chan struct{})
+    go store.StartExpiryJanitor("./data", time.Minute, janitorStop)
 
     mux := http.NewServeMux()
     mux.HandleFunc("/", s.Handler)
@@ -38,6 +64,7 @@ func main() {
     <-stop
 
     log.Println("shutting down...")
+    close(janitorStop)
     ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
     defer cancel()
     if err := srv.Shutdown(ctx); err != nil {
@@ -45,3 +72,58 @@ func main() {
     }
     log.Println("server exited")
 }
+
+func newFileStore(backend, s3Endpoint string) (*store.FileStore, error) {
+    switch backend {
+    case "", "fs":
+        return store.NewFileStore("./data"), nil
+    case "s3":
+        s3Backend, err := store.NewS3Backend(context.Background(), s3Endpoint, s3Endpoint != "")
+        if err != nil {
+            return nil, err
+        }
+        return store.NewWithBackend("./data", s3Backend), nil
+    default:
+        return nil, fmt.Errorf("unknown backend %q (want fs or s3)", backend)
+    }
+}
+
+func envOrDefault(key, def string) string {
+    if v := os.Getenv(key); v != "" {
+        return v
+    }
+    return def
+}
+
+// runPresign implements
+// `mini-s3 presign -method GET -key foo/bar -ttl 10m -key-id ID -keys keys.json`
+// (-keys defaults to $KEYS_FILE), emitting a ready-to-use presigned URL for
+// the given key id's secret.
+func runPresign(args []string) {
+    fset := flag.NewFlagSet("presign", flag.ExitOnError)
+    method := fset.String("method", http.MethodGet, "HTTP method the URL is valid for")
+    key := fset.String("key", "", "bucket/key to sign, e.g. mybucket/path/to/object")
+    query := fset.String("query", "", "operation-selecting query string the URL is restricted to, e.g. list-type=2")
+    ttl := fset.Duration("ttl", 10*time.Minute, "how long the URL remains valid")
+    keyID := fset.String("key-id", "", "key id to sign with (required)")
+    keysFile := fset.String("keys", os.Getenv("KEYS_FILE"), "path to the JSON keys file")
+    baseURL := fset.String("base-url", "http://localhost:8080", "server base URL to prefix the presigned path with")
+    fset.Parse(args)
+
+    if *key == "" || *keyID == "" || *keysFile == "" {
+        log.Fatal("presign requires -key, -key-id, and -keys")
+    }
+
+    keys, err := store.LoadKeysFile(*keysFile)
+    if err != nil {
+        log.Fatalf("failed to load keys file %s: %s", *keysFile, err)
+    }
+
+    secret, ok := keys[*keyID]
+    if !ok {
+        log.Fatalf("unknown key id %q in %s", *keyID, *keysFile)
+    }
+
+    url := store.PresignURL(*baseURL, *keyID, secret, *method, "/"+*key, *query, *ttl)
+    fmt.Println(url)
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+    "archive/tar"
+    "archive/zip"
+    "compress/gzip"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// handleArchive handles GET /{bucket}/?archive=tar|tar.gz|zip&prefix=foo/,
+// streaming every object under the bucket (optionally filtered by prefix)
+// as a single archive without buffering it in memory.
+func (fs *FileStore) handleArchive(w http.ResponseWriter, r *http.Request, bucket, format, prefix string) {
+    bucketDir := filepath.Join(fs.root, bucket)
+
+    filename := bucket + archiveExtension(format)
+    switch format {
+    case "zip":
+        w.Header().Set("Content-Type", "application/zip")
+    case "tar.gz":
+        w.Header().Set("Content-Type", "application/gzip")
+    case "tar":
+        w.Header().Set("Content-Type", "application/x-tar")
+    default:
+        http.Error(w, "unsupported archive format", http.StatusBadRequest)
+        return
+    }
+    w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+    switch format {
+    case "zip":
+        writeZipArchive(w, bucketDir, prefix)
+    case "tar.gz":
+        gw := gzip.NewWriter(w)
+        defer gw.Close()
+        writeTarArchive(gw, bucketDir, prefix)
+    case "tar":
+        writeTarArchive(w, bucketDir, prefix)
+    }
+}
+
+func archiveExtension(format string) string {
+    switch format {
+    case "zip":
+        return ".zip"
+    case "tar.gz":
+        return ".tar.gz"
+    default:
+        return ".tar"
+    }
+}
+
+func writeTarArchive(w io.Writer, bucketDir, prefix string) {
+    tw := tar.NewWriter(w)
+    defer tw.Close()
+
+    walkArchivePrefix(bucketDir, prefix, func(relPath string, info os.FileInfo, path string) error {
+        hdr, err := tar.FileInfoHeader(info, "")
+        if err != nil {
+            return err
+        }
+        hdr.Name = relPath
+        if err := tw.WriteHeader(hdr); err != nil {
+            return err
+        }
+
+        f, err := os.Open(path)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+
+        _, err = io.Copy(tw, f)
+        return err
+    })
+}
+
+func writeZipArchive(w http.ResponseWriter, bucketDir, prefix string) {
+    zw := zip.NewWriter(w)
+    defer zw.Close()
+
+    walkArchivePrefix(bucketDir, prefix, func(relPath string, info os.FileInfo, path string) error {
+        hdr, err := zip.FileInfoHeader(info)
+        if err != nil {
+            return err
+        }
+        hdr.Name = relPath
+        hdr.Method = zip.Store
+
+        entry, err := zw.CreateHeader(hdr)
+        if err != nil {
+            return err
+        }
+
+        f, err := os.Open(path)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+
+        _, err = io.Copy(entry, f)
+        return err
+    })
+}
+
+// walkArchivePrefix walks bucketDir, skipping metadata sidecars and any
+// path not matching prefix, invoking fn with the archive-relative name.
+func walkArchivePrefix(bucketDir, prefix string, fn func(relPath string, info os.FileInfo, path string) error) {
+    filepath.Walk(bucketDir, func(path string, info os.FileInfo, err error) error {
+        if err != nil || info.IsDir() {
+            return nil
+        }
+        if isSidecarPath(path) {
+            return nil
+        }
+
+        relPath, err := filepath.Rel(bucketDir, path)
+        if err != nil {
+            return nil
+        }
+        relPath = filepath.ToSlash(relPath)
+
+        if prefix != "" && !strings.HasPrefix(relPath, prefix) {
+            return nil
+        }
+
+        return fn(relPath, info, path)
+    })
+}
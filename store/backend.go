@@ -0,0 +1,47 @@
+package store
+
+import (
+    "context"
+    "errors"
+    "io"
+    "time"
+)
+
+// ErrNotFound is returned (or wrapped) by Backend.Get/Head/Delete when the
+// requested object doesn't exist, so HTTP handlers can map it to a 404
+// without knowing which backend is in play - os.IsNotExist only recognizes
+// *os.PathError, which S3Backend never returns.
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes an object independent of which Backend served it.
+type ObjectInfo struct {
+    Key          string
+    Size         int64
+    ETag         string
+    LastModified time.Time
+    ContentType  string
+    UserMeta     map[string]string
+
+    // Expiry is the time after which the object should be deleted by the
+    // janitor. Zero means no expiry. Only honored by backends that
+    // implement their own expiry sweep; S3Backend ignores it for now.
+    Expiry time.Time
+}
+
+// Backend is the storage abstraction behind FileStore's HTTP handlers. The
+// local filesystem implementation lives directly on *FileStore; S3Backend
+// proxies the same operations to a real S3 or MinIO endpoint.
+type Backend interface {
+    Put(ctx context.Context, bucket, key string, r io.Reader, info ObjectInfo) (ObjectInfo, error)
+    Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error)
+    Head(ctx context.Context, bucket, key string) (ObjectInfo, error)
+    Delete(ctx context.Context, bucket, key string) error
+    List(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error)
+}
+
+// RangeReader is implemented by backends that can serve a byte range
+// directly, letting the HTTP layer translate a Range header through to the
+// backend instead of seeking a local file.
+type RangeReader interface {
+    GetRange(ctx context.Context, bucket, key string, start, end int64) (io.ReadCloser, error)
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+    "container/list"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "os"
+    "sync"
+
+    "github.com/pkg/xattr"
+)
+
+const etagXattrName = "user.minis3.sha256"
+
+// etagRecord is the cached digest alongside the stat values it was
+// computed from, so a stale cache entry can be detected cheaply without
+// re-reading the file.
+type etagRecord struct {
+    SHA256 string `json:"sha256"`
+    MTime  int64  `json:"mtime"`
+    Size   int64  `json:"size"`
+}
+
+// etagSuffix names the xattr-fallback ETag sidecar file (see metaSuffix in
+// metadata.go and isSidecarPath, which filters both out of bucket walks).
+const etagSuffix = ".etag"
+
+func etagSidecarPath(path string) string {
+    return path + etagSuffix
+}
+
+func readETagXattr(path string) (*etagRecord, error) {
+    data, err := xattr.Get(path, etagXattrName)
+    if err != nil {
+        return nil, err
+    }
+    var rec etagRecord
+    if err := json.Unmarshal(data, &rec); err != nil {
+        return nil, err
+    }
+    return &rec, nil
+}
+
+func writeETagXattr(path string, rec *etagRecord) error {
+    data, err := json.Marshal(rec)
+    if err != nil {
+        return err
+    }
+    return xattr.Set(path, etagXattrName, data)
+}
+
+func readETagSidecar(path string) (*etagRecord, error) {
+    data, err := os.ReadFile(etagSidecarPath(path))
+    if err != nil {
+        return nil, err
+    }
+    var rec etagRecord
+    if err := json.Unmarshal(data, &rec); err != nil {
+        return nil, err
+    }
+    return &rec, nil
+}
+
+func writeETagSidecar(path string, rec *etagRecord) error {
+    data, err := json.Marshal(rec)
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(etagSidecarPath(path), data, 0o644)
+}
+
+// storeETag persists rec as an xattr on path, falling back to a {path}.etag
+// sidecar on filesystems without xattr support.
+func storeETag(path string, rec *etagRecord) error {
+    if err := writeETagXattr(path, rec); err == nil {
+        return nil
+    }
+    return writeETagSidecar(path, rec)
+}
+
+// loadStoredETag reads whichever of the xattr or sidecar cache is
+// populated for path.
+func loadStoredETag(path string) (*etagRecord, error) {
+    if rec, err := readETagXattr(path); err == nil {
+        return rec, nil
+    }
+    return readETagSidecar(path)
+}
+
+// etagCacheKey identifies an LRU entry by the stat values the digest was
+// computed from, so a file that changes on disk invalidates itself.
+type etagCacheKey struct {
+    path  string
+    mtime int64
+    size  int64
+}
+
+// lruCache is a bounded in-memory cache keyed by (path, mtime, size), so a
+// file that changes on disk invalidates its own entry. FileStore keeps two
+// instances: etagCache for etagForFile's no-sidecar fallback digests, and
+// metaCache for loadMetaCached's sidecar contents - the latter is what's
+// actually exercised on every GET/HEAD of a normal object.
+type lruCache struct {
+    mu       sync.Mutex
+    capacity int
+    ll       *list.List
+    items    map[etagCacheKey]*list.Element
+}
+
+type lruCacheEntry struct {
+    key   etagCacheKey
+    value any
+}
+
+func newLRUCache(capacity int) *lruCache {
+    return &lruCache{
+        capacity: capacity,
+        ll:       list.New(),
+        items:    make(map[etagCacheKey]*list.Element),
+    }
+}
+
+func (c *lruCache) get(key etagCacheKey) (any, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    el, ok := c.items[key]
+    if !ok {
+        return nil, false
+    }
+    c.ll.MoveToFront(el)
+    return el.Value.(*lruCacheEntry).value, true
+}
+
+func (c *lruCache) add(key etagCacheKey, value any) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if el, ok := c.items[key]; ok {
+        c.ll.MoveToFront(el)
+        el.Value.(*lruCacheEntry).value = value
+        return
+    }
+
+    el := c.ll.PushFront(&lruCacheEntry{key: key, value: value})
+    c.items[key] = el
+
+    for c.ll.Len() > c.capacity {
+        oldest := c.ll.Back()
+        if oldest == nil {
+            break
+        }
+        c.ll.Remove(oldest)
+        delete(c.items, oldest.Value.(*lruCacheEntry).key)
+    }
+}
+
+// etagForFile returns the SHA-256 digest of path, preferring the cached
+// value (LRU, then xattr, then sidecar) over re-reading the whole file,
+// and only recomputing when the cached mtime/size no longer matches stat.
+func (fs *FileStore) etagForFile(path string, stat os.FileInfo) (string, error) {
+    key := etagCacheKey{path: path, mtime: stat.ModTime().UnixNano(), size: stat.Size()}
+
+    if v, ok := fs.etagCache.get(key); ok {
+        return v.(string), nil
+    }
+
+    if rec, err := loadStoredETag(path); err == nil {
+        if rec.MTime == key.mtime && rec.Size == key.size {
+            fs.etagCache.add(key, rec.SHA256)
+            return rec.SHA256, nil
+        }
+    }
+
+    digest, err := hashFile(path)
+    if err != nil {
+        return "", err
+    }
+
+    rec := &etagRecord{SHA256: digest, MTime: key.mtime, Size: key.size}
+    storeETag(path, rec)
+    fs.etagCache.add(key, digest)
+
+    return digest, nil
+}
+
+func hashFile(path string) (string, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+
+    h := sha256.New()
+    if _, err := io.Copy(h, f); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// Put implements Backend by writing r to the local filesystem and
+// persisting the metadata sidecar alongside it.
+func (fs *FileStore) Put(ctx context.Context, bucket, key string, r io.Reader, info ObjectInfo) (ObjectInfo, error) {
+    path := fs.fullPath(bucket, key)
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return ObjectInfo{}, err
+    }
+
+    file, err := os.Create(path)
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+    defer file.Close()
+
+    hasher := sha256.New()
+    if _, err := io.Copy(io.MultiWriter(file, hasher), r); err != nil {
+        os.Remove(path)
+        return ObjectInfo{}, err
+    }
+
+    stat, err := file.Stat()
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+
+    etag := hex.EncodeToString(hasher.Sum(nil))
+    deleteKey, err := newDeleteKey()
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+
+    meta := &objectMeta{
+        ContentType: info.ContentType,
+        UserMeta:    info.UserMeta,
+        DeleteKey:   deleteKey,
+        Size:        stat.Size(),
+        SHA256Sum:   etag,
+    }
+    if !info.Expiry.IsZero() {
+        meta.Expiry = info.Expiry.Unix()
+    }
+    if err := fs.saveMeta(path, meta); err != nil {
+        return ObjectInfo{}, err
+    }
+
+    rec := &etagRecord{SHA256: etag, MTime: stat.ModTime().UnixNano(), Size: stat.Size()}
+    storeETag(path, rec)
+    fs.etagCache.add(etagCacheKey{path: path, mtime: rec.MTime, size: rec.Size}, etag)
+
+    return ObjectInfo{
+        Key:          key,
+        Size:         stat.Size(),
+        ETag:         etag,
+        LastModified: stat.ModTime(),
+        ContentType:  info.ContentType,
+        UserMeta:     info.UserMeta,
+    }, nil
+}
+
+// Get implements Backend by opening the object from the local filesystem.
+func (fs *FileStore) Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+    path := fs.fullPath(bucket, key)
+    file, err := os.Open(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, ObjectInfo{}, ErrNotFound
+        }
+        return nil, ObjectInfo{}, err
+    }
+
+    info, err := fs.statInfo(path, key, file)
+    if err != nil {
+        file.Close()
+        return nil, ObjectInfo{}, err
+    }
+
+    return file, info, nil
+}
+
+// Head implements Backend without opening the object's contents.
+func (fs *FileStore) Head(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+    path := fs.fullPath(bucket, key)
+    stat, err := os.Stat(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return ObjectInfo{}, ErrNotFound
+        }
+        return ObjectInfo{}, err
+    }
+    return fs.statInfoFromFileInfo(path, key, stat)
+}
+
+// Delete implements Backend by removing the object and its sidecar.
+func (fs *FileStore) Delete(ctx context.Context, bucket, key string) error {
+    path := fs.fullPath(bucket, key)
+    if err := os.Remove(path); err != nil {
+        if os.IsNotExist(err) {
+            return ErrNotFound
+        }
+        return err
+    }
+    os.Remove(metaPath(path))
+    return nil
+}
+
+// List implements Backend by walking the bucket directory under prefix.
+func (fs *FileStore) List(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+    bucketDir := filepath.Join(fs.root, bucket)
+
+    var objects []ObjectInfo
+    err := filepath.Walk(bucketDir, func(path string, fi os.FileInfo, err error) error {
+        if err != nil || fi.IsDir() || isSidecarPath(path) {
+            return nil
+        }
+
+        relPath, err := filepath.Rel(bucketDir, path)
+        if err != nil {
+            return nil
+        }
+        relPath = filepath.ToSlash(relPath)
+        if prefix != "" && !strings.HasPrefix(relPath, prefix) {
+            return nil
+        }
+
+        info, err := fs.statInfoFromFileInfo(path, relPath, fi)
+        if err != nil {
+            return nil
+        }
+        objects = append(objects, info)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return objects, nil
+}
+
+// GetRange implements RangeReader for the local filesystem backend.
+func (fs *FileStore) GetRange(ctx context.Context, bucket, key string, start, end int64) (io.ReadCloser, error) {
+    path := fs.fullPath(bucket, key)
+    file, err := os.Open(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, ErrNotFound
+        }
+        return nil, err
+    }
+    if _, err := file.Seek(start, 0); err != nil {
+        file.Close()
+        return nil, err
+    }
+    return &limitedReadCloser{r: io.LimitReader(file, end-start+1), c: file}, nil
+}
+
+type limitedReadCloser struct {
+    r io.Reader
+    c io.Closer
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) { return l.r.Read(p) }
+func (l *limitedReadCloser) Close() error               { return l.c.Close() }
+
+func (fs *FileStore) statInfo(path, key string, file *os.File) (ObjectInfo, error) {
+    stat, err := file.Stat()
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+    return fs.statInfoFromFileInfo(path, key, stat)
+}
+
+func (fs *FileStore) statInfoFromFileInfo(path, key string, stat os.FileInfo) (ObjectInfo, error) {
+    info := ObjectInfo{
+        Key:          key,
+        Size:         stat.Size(),
+        LastModified: stat.ModTime(),
+    }
+
+    if meta, err := fs.loadMetaCached(path, stat); err == nil {
+        info.ETag = meta.SHA256Sum
+        info.ContentType = meta.ContentType
+        info.UserMeta = meta.UserMeta
+        return info, nil
+    }
+
+    if etag, err := fs.etagForFile(path, stat); err == nil {
+        info.ETag = etag
+    }
+
+    return info, nil
+}
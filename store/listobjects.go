@@ -0,0 +1,133 @@
+package store
+
+import (
+    "encoding/base64"
+    "encoding/xml"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    defaultMaxKeys = 1000
+    maxMaxKeys     = 1000
+)
+
+// listBucketResult mirrors the S3 ListObjectsV2 XML response closely
+// enough for generic S3 SDKs (aws-cli, rclone, ...) to enumerate a bucket.
+type listBucketResult struct {
+    XMLName               xml.Name       `xml:"ListBucketResult"`
+    Name                  string         `xml:"Name"`
+    Prefix                string         `xml:"Prefix"`
+    Delimiter             string         `xml:"Delimiter,omitempty"`
+    MaxKeys               int            `xml:"MaxKeys"`
+    IsTruncated           bool           `xml:"IsTruncated"`
+    ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+    NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+    Contents              []listContent  `xml:"Contents"`
+    CommonPrefixes        []commonPrefix `xml:"CommonPrefixes,omitempty"`
+}
+
+type listContent struct {
+    Key          string `xml:"Key"`
+    LastModified string `xml:"LastModified"`
+    ETag         string `xml:"ETag"`
+    Size         int64  `xml:"Size"`
+}
+
+type commonPrefix struct {
+    Prefix string `xml:"Prefix"`
+}
+
+// handleListObjects handles GET /{bucket}/?list-type=2&prefix=&delimiter=
+// &continuation-token=&max-keys=, walking the bucket via Backend.List and
+// paginating on a base64-encoded last-key continuation token.
+func (fs *FileStore) handleListObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+    q := r.URL.Query()
+    prefix := q.Get("prefix")
+    delimiter := q.Get("delimiter")
+
+    maxKeys := defaultMaxKeys
+    if raw := q.Get("max-keys"); raw != "" {
+        if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+            maxKeys = n
+        }
+    }
+    if maxKeys > maxMaxKeys {
+        maxKeys = maxMaxKeys
+    }
+
+    var after string
+    if token := q.Get("continuation-token"); token != "" {
+        decoded, err := base64.StdEncoding.DecodeString(token)
+        if err != nil {
+            http.Error(w, "invalid continuation-token", http.StatusBadRequest)
+            return
+        }
+        after = string(decoded)
+    }
+
+    objects, err := fs.backend.List(r.Context(), bucket, prefix)
+    if err != nil {
+        http.Error(w, "failed to list bucket", http.StatusInternalServerError)
+        return
+    }
+    sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+    if after != "" {
+        start := sort.Search(len(objects), func(i int) bool { return objects[i].Key > after })
+        objects = objects[start:]
+    }
+
+    result := listBucketResult{
+        Name:      bucket,
+        Prefix:    prefix,
+        Delimiter: delimiter,
+        MaxKeys:   maxKeys,
+    }
+
+    seenPrefixes := make(map[string]bool)
+    var lastKey string
+    count := 0
+
+    for _, obj := range objects {
+        if count >= maxKeys {
+            result.IsTruncated = true
+            break
+        }
+
+        if delimiter != "" {
+            rest := strings.TrimPrefix(obj.Key, prefix)
+            if idx := strings.Index(rest, delimiter); idx >= 0 {
+                cp := prefix + rest[:idx+len(delimiter)]
+                if !seenPrefixes[cp] {
+                    seenPrefixes[cp] = true
+                    result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: cp})
+                    count++
+                }
+                lastKey = obj.Key
+                continue
+            }
+        }
+
+        result.Contents = append(result.Contents, listContent{
+            Key:          obj.Key,
+            LastModified: obj.LastModified.UTC().Format(time.RFC3339),
+            ETag:         `"` + obj.ETag + `"`,
+            Size:         obj.Size,
+        })
+        count++
+        lastKey = obj.Key
+    }
+
+    if result.IsTruncated {
+        result.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(lastKey))
+    }
+
+    w.Header().Set("Content-Type", "application/xml")
+    w.WriteHeader(http.StatusOK)
+    w.Write([]byte(xml.Header))
+    xml.NewEncoder(w).Encode(result)
+}
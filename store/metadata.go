@@ -0,0 +1,160 @@
+package store
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// objectMeta is the sidecar persisted alongside every object, storing the
+// information needed to replay the original PUT request's headers on GET
+// and HEAD, and to gate deletion and expiry.
+type objectMeta struct {
+    ContentType string            `json:"content_type,omitempty"`
+    UserMeta    map[string]string `json:"user_meta,omitempty"`
+    DeleteKey   string            `json:"delete_key,omitempty"`
+    Size        int64             `json:"size"`
+    SHA256Sum   string            `json:"sha256sum"`
+    Expiry      int64             `json:"expiry,omitempty"`
+}
+
+// metaSuffix names the metadata sidecar file, and etagSuffix (defined in
+// etagcache.go) the xattr-fallback ETag sidecar. Neither is itself an
+// object, so List and archive streaming filter both out of their bucket
+// walks with isSidecarPath; the expiry janitor reads only the metadata
+// sidecars.
+const metaSuffix = ".meta.json"
+
+func metaPath(path string) string {
+    return path + metaSuffix
+}
+
+// isSidecarPath reports whether path is a sidecar file FileStore writes
+// alongside an object (metadata or xattr-fallback ETag cache), rather than
+// the object itself.
+func isSidecarPath(path string) bool {
+    return strings.HasSuffix(path, metaSuffix) || strings.HasSuffix(path, etagSuffix)
+}
+
+func (fs *FileStore) loadMeta(path string) (*objectMeta, error) {
+    data, err := os.ReadFile(metaPath(path))
+    if err != nil {
+        return nil, err
+    }
+    var m objectMeta
+    if err := json.Unmarshal(data, &m); err != nil {
+        return nil, err
+    }
+    return &m, nil
+}
+
+// loadMetaCached behaves like loadMeta but short-circuits the sidecar read
+// through fs.metaCache when stat's mtime/size match a cached entry, since
+// this runs on every GET/HEAD of an object that has a sidecar - i.e. nearly
+// every object, now that Put and multipart Complete both write one.
+func (fs *FileStore) loadMetaCached(path string, stat os.FileInfo) (*objectMeta, error) {
+    key := etagCacheKey{path: path, mtime: stat.ModTime().UnixNano(), size: stat.Size()}
+    if v, ok := fs.metaCache.get(key); ok {
+        return v.(*objectMeta), nil
+    }
+
+    m, err := fs.loadMeta(path)
+    if err != nil {
+        return nil, err
+    }
+    fs.metaCache.add(key, m)
+    return m, nil
+}
+
+func (fs *FileStore) saveMeta(path string, m *objectMeta) error {
+    data, err := json.Marshal(m)
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(metaPath(path), data, 0o644)
+}
+
+func newDeleteKey() (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+// checkDeleteKey enforces the delete_key stored in an object's sidecar,
+// unless delete-key enforcement has been disabled.
+func (fs *FileStore) checkDeleteKey(r *http.Request, m *objectMeta) bool {
+    if !fs.RequireDeleteKey {
+        return true
+    }
+    if m == nil || m.DeleteKey == "" {
+        return true
+    }
+
+    provided := r.Header.Get("X-Delete-Key")
+    if provided == "" {
+        provided = r.URL.Query().Get("delete_key")
+    }
+    return provided == m.DeleteKey
+}
+
+// expiredObjects walks root looking for objects whose sidecar expiry has
+// passed, returning their object paths (not the sidecar paths).
+func expiredObjects(root string, now time.Time) ([]string, error) {
+    var expired []string
+    err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        if info.IsDir() || !strings.HasSuffix(path, metaSuffix) {
+            return nil
+        }
+
+        data, err := os.ReadFile(path)
+        if err != nil {
+            return nil
+        }
+        var m objectMeta
+        if err := json.Unmarshal(data, &m); err != nil {
+            return nil
+        }
+        if m.Expiry == 0 || now.Unix() < m.Expiry {
+            return nil
+        }
+
+        expired = append(expired, strings.TrimSuffix(path, metaSuffix))
+        return nil
+    })
+    return expired, err
+}
+
+// StartExpiryJanitor periodically scans root for objects past their
+// X-Expire-Seconds expiry and removes them along with their sidecars. It
+// runs until stop is closed.
+func StartExpiryJanitor(root string, interval time.Duration, stop <-chan struct{}) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-stop:
+            return
+        case now := <-ticker.C:
+            paths, err := expiredObjects(root, now)
+            if err != nil {
+                continue
+            }
+            for _, path := range paths {
+                os.Remove(path)
+                os.Remove(metaPath(path))
+                os.Remove(etagSidecarPath(path))
+            }
+        }
+    }
+}
@@ -0,0 +1,319 @@
+package store
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "sync"
+)
+
+// multipartManifest tracks the state of an in-progress multipart upload so
+// that it can be resumed or completed even after a server restart.
+type multipartManifest struct {
+    UploadID string                 `json:"upload_id"`
+    Bucket   string                 `json:"bucket"`
+    Key      string                 `json:"key"`
+    Parts    map[int]multipartPart  `json:"parts"`
+}
+
+type multipartPart struct {
+    PartNumber int    `json:"part_number"`
+    ETag       string `json:"etag"`
+    Size       int64  `json:"size"`
+}
+
+type completePart struct {
+    PartNumber int    `json:"partNumber"`
+    ETag       string `json:"etag"`
+}
+
+type completeRequest struct {
+    Parts []completePart `json:"parts"`
+}
+
+func (fs *FileStore) uploadDir(uploadID string) string {
+    return filepath.Join(fs.root, ".uploads", uploadID)
+}
+
+func (fs *FileStore) manifestPath(uploadID string) string {
+    return filepath.Join(fs.uploadDir(uploadID), "manifest.json")
+}
+
+func (fs *FileStore) partPath(uploadID string, partNumber int) string {
+    return filepath.Join(fs.uploadDir(uploadID), strconv.Itoa(partNumber))
+}
+
+func newUploadID() (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+// lockUpload serializes access to a single uploadID's manifest, since S3
+// clients upload parts concurrently and a bare load-mutate-save around
+// manifest.json would otherwise race. The returned func releases the lock.
+func (fs *FileStore) lockUpload(uploadID string) func() {
+    v, _ := fs.uploadLocks.LoadOrStore(uploadID, &sync.Mutex{})
+    mu := v.(*sync.Mutex)
+    mu.Lock()
+    return mu.Unlock
+}
+
+func (fs *FileStore) loadManifest(uploadID string) (*multipartManifest, error) {
+    data, err := os.ReadFile(fs.manifestPath(uploadID))
+    if err != nil {
+        return nil, err
+    }
+    var m multipartManifest
+    if err := json.Unmarshal(data, &m); err != nil {
+        return nil, err
+    }
+    return &m, nil
+}
+
+func (fs *FileStore) saveManifest(m *multipartManifest) error {
+    data, err := json.Marshal(m)
+    if err != nil {
+        return err
+    }
+    tmp := fs.manifestPath(m.UploadID) + ".tmp"
+    if err := os.WriteFile(tmp, data, 0o644); err != nil {
+        return err
+    }
+    return os.Rename(tmp, fs.manifestPath(m.UploadID))
+}
+
+// handleInitiateMultipart handles POST /{bucket}/{key}?uploads
+func (fs *FileStore) handleInitiateMultipart(w http.ResponseWriter, r *http.Request, bucket, key string) {
+    uploadID, err := newUploadID()
+    if err != nil {
+        http.Error(w, "failed to create upload id", http.StatusInternalServerError)
+        return
+    }
+
+    if err := os.MkdirAll(fs.uploadDir(uploadID), 0o755); err != nil {
+        http.Error(w, "failed to create upload directory", http.StatusInternalServerError)
+        return
+    }
+
+    m := &multipartManifest{
+        UploadID: uploadID,
+        Bucket:   bucket,
+        Key:      key,
+        Parts:    map[int]multipartPart{},
+    }
+    if err := fs.saveManifest(m); err != nil {
+        http.Error(w, "failed to persist upload", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusOK)
+    json.NewEncoder(w).Encode(map[string]string{"uploadId": uploadID})
+}
+
+// handleUploadPart handles PUT /{bucket}/{key}?partNumber=N&uploadId=...
+func (fs *FileStore) handleUploadPart(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string, partNumber int) {
+    unlock := fs.lockUpload(uploadID)
+    defer unlock()
+
+    m, err := fs.loadManifest(uploadID)
+    if err != nil {
+        http.Error(w, "upload not found", http.StatusNotFound)
+        return
+    }
+    if m.Bucket != bucket || m.Key != key {
+        http.Error(w, "upload does not match bucket/key", http.StatusBadRequest)
+        return
+    }
+
+    path := fs.partPath(uploadID, partNumber)
+    file, err := os.Create(path)
+    if err != nil {
+        http.Error(w, "failed to create part", http.StatusInternalServerError)
+        return
+    }
+    defer file.Close()
+
+    hasher := sha256.New()
+    mw := io.MultiWriter(file, hasher)
+
+    written, err := io.Copy(mw, r.Body)
+    if err != nil {
+        os.Remove(path)
+        http.Error(w, "failed to write part", http.StatusInternalServerError)
+        return
+    }
+
+    etag := hex.EncodeToString(hasher.Sum(nil))
+
+    if boundHash := boundBodySHA256(r); boundHash != "" && boundHash != etag {
+        os.Remove(path)
+        http.Error(w, "body does not match signed content hash", http.StatusBadRequest)
+        return
+    }
+
+    m.Parts[partNumber] = multipartPart{PartNumber: partNumber, ETag: etag, Size: written}
+    if err := fs.saveManifest(m); err != nil {
+        http.Error(w, "failed to persist part", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("ETag", `"`+etag+`"`)
+    w.WriteHeader(http.StatusOK)
+}
+
+// handleCompleteMultipart handles POST /{bucket}/{key}?uploadId=...
+func (fs *FileStore) handleCompleteMultipart(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+    unlock := fs.lockUpload(uploadID)
+    // Defers run LIFO, so the map entry must be registered for deletion
+    // before the unlock, or it deletes before the mutex actually unlocks -
+    // leaving a window where a concurrent call for uploadID LoadOrStores a
+    // fresh, uncontended mutex instead of blocking on this one.
+    defer fs.uploadLocks.Delete(uploadID)
+    defer unlock()
+
+    m, err := fs.loadManifest(uploadID)
+    if err != nil {
+        http.Error(w, "upload not found", http.StatusNotFound)
+        return
+    }
+    if m.Bucket != bucket || m.Key != key {
+        http.Error(w, "upload does not match bucket/key", http.StatusBadRequest)
+        return
+    }
+
+    var req completeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "invalid completion manifest", http.StatusBadRequest)
+        return
+    }
+    if len(req.Parts) == 0 {
+        http.Error(w, "no parts specified", http.StatusBadRequest)
+        return
+    }
+
+    sort.Slice(req.Parts, func(i, j int) bool { return req.Parts[i].PartNumber < req.Parts[j].PartNumber })
+
+    partETags := make([]string, 0, len(req.Parts))
+    for _, p := range req.Parts {
+        stored, ok := m.Parts[p.PartNumber]
+        if !ok {
+            http.Error(w, fmt.Sprintf("part %d not uploaded", p.PartNumber), http.StatusBadRequest)
+            return
+        }
+        if stored.ETag != p.ETag {
+            http.Error(w, fmt.Sprintf("etag mismatch for part %d", p.PartNumber), http.StatusBadRequest)
+            return
+        }
+        partETags = append(partETags, stored.ETag)
+    }
+
+    etag, err := compositeETag(partETags)
+    if err != nil {
+        http.Error(w, "corrupt part etag", http.StatusInternalServerError)
+        return
+    }
+
+    path := fs.fullPath(bucket, key)
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        http.Error(w, "failed to create directory", http.StatusInternalServerError)
+        return
+    }
+
+    out, err := os.Create(path)
+    if err != nil {
+        http.Error(w, "failed to create object", http.StatusInternalServerError)
+        return
+    }
+    defer out.Close()
+
+    var size int64
+    for _, p := range req.Parts {
+        n, err := fs.appendPart(out, uploadID, p.PartNumber)
+        if err != nil {
+            out.Close()
+            os.Remove(path)
+            http.Error(w, "failed to assemble object", http.StatusInternalServerError)
+            return
+        }
+        size += n
+    }
+
+    deleteKey, err := newDeleteKey()
+    if err != nil {
+        os.Remove(path)
+        http.Error(w, "failed to finalize object", http.StatusInternalServerError)
+        return
+    }
+    if err := fs.saveMeta(path, &objectMeta{DeleteKey: deleteKey, Size: size, SHA256Sum: etag}); err != nil {
+        os.Remove(path)
+        http.Error(w, "failed to finalize object", http.StatusInternalServerError)
+        return
+    }
+
+    os.RemoveAll(fs.uploadDir(uploadID))
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusOK)
+    json.NewEncoder(w).Encode(map[string]string{"etag": etag})
+}
+
+// compositeETag computes the S3-style multipart ETag: the hex SHA-256 of
+// the concatenated (decoded) part digests, suffixed with the part count.
+func compositeETag(partETags []string) (string, error) {
+    digests := make([]byte, 0, len(partETags)*sha256.Size)
+    for _, etag := range partETags {
+        raw, err := hex.DecodeString(etag)
+        if err != nil {
+            return "", fmt.Errorf("corrupt part etag %q: %w", etag, err)
+        }
+        digests = append(digests, raw...)
+    }
+    sum := sha256.Sum256(digests)
+    return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(partETags)), nil
+}
+
+func (fs *FileStore) appendPart(out *os.File, uploadID string, partNumber int) (int64, error) {
+    part, err := os.Open(fs.partPath(uploadID, partNumber))
+    if err != nil {
+        return 0, err
+    }
+    defer part.Close()
+
+    return io.Copy(out, part)
+}
+
+// handleAbortMultipart handles DELETE /{bucket}/{key}?uploadId=...
+func (fs *FileStore) handleAbortMultipart(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+    unlock := fs.lockUpload(uploadID)
+    defer fs.uploadLocks.Delete(uploadID)
+    defer unlock()
+
+    m, err := fs.loadManifest(uploadID)
+    if err != nil {
+        http.Error(w, "upload not found", http.StatusNotFound)
+        return
+    }
+    if m.Bucket != bucket || m.Key != key {
+        http.Error(w, "upload does not match bucket/key", http.StatusBadRequest)
+        return
+    }
+
+    if err := os.RemoveAll(fs.uploadDir(uploadID)); err != nil {
+        http.Error(w, "failed to abort upload", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
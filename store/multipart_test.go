@@ -0,0 +1,73 @@
+package store
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "sync"
+    "testing"
+)
+
+func digestOf(s string) string {
+    sum := sha256.Sum256([]byte(s))
+    return hex.EncodeToString(sum[:])
+}
+
+func TestCompositeETag(t *testing.T) {
+    part1, part2 := digestOf("part-one"), digestOf("part-two")
+
+    got, err := compositeETag([]string{part1, part2})
+    if err != nil {
+        t.Fatalf("compositeETag: %v", err)
+    }
+
+    raw1, _ := hex.DecodeString(part1)
+    raw2, _ := hex.DecodeString(part2)
+    sum := sha256.Sum256(append(append([]byte{}, raw1...), raw2...))
+    want := hex.EncodeToString(sum[:]) + "-2"
+
+    if got != want {
+        t.Errorf("compositeETag = %q, want %q", got, want)
+    }
+}
+
+func TestCompositeETagEmpty(t *testing.T) {
+    got, err := compositeETag(nil)
+    if err != nil {
+        t.Fatalf("compositeETag: %v", err)
+    }
+    want := hex.EncodeToString(sha256.New().Sum(nil)) + "-0"
+    if got != want {
+        t.Errorf("compositeETag(nil) = %q, want %q", got, want)
+    }
+}
+
+func TestCompositeETagRejectsCorruptDigest(t *testing.T) {
+    if _, err := compositeETag([]string{"not-hex"}); err == nil {
+        t.Fatal("expected error for non-hex part etag, got nil")
+    }
+}
+
+// TestLockUploadSerializesConcurrentUpdates exercises fs.lockUpload the way
+// handleUploadPart does, confirming concurrent callers are serialized rather
+// than racing on a shared map.
+func TestLockUploadSerializesConcurrentUpdates(t *testing.T) {
+    fs := &FileStore{}
+    m := map[int]int{}
+
+    var wg sync.WaitGroup
+    for i := 1; i <= 50; i++ {
+        i := i
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            unlock := fs.lockUpload("upload-1")
+            defer unlock()
+            m[i] = i
+        }()
+    }
+    wg.Wait()
+
+    if len(m) != 50 {
+        t.Errorf("got %d entries, want 50 (a race would drop some)", len(m))
+    }
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/config"
+    "github.com/aws/aws-sdk-go-v2/service/s3"
+    "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// userMetaPrefix is the header prefix store.go's handlePut uses as the key
+// for ObjectInfo.UserMeta (e.g. "X-Amz-Meta-Foo"). The S3 SDK's Metadata map
+// uses bare, lowercased names instead - its header encoder/decoder adds and
+// strips the x-amz-meta- prefix itself - so Put and objectInfoFromGet must
+// translate explicitly or the prefix doubles up on the wire.
+const userMetaPrefix = "X-Amz-Meta-"
+
+// s3Metadata strips userMetaPrefix off UserMeta's keys for the SDK's
+// Metadata map, which re-adds it when serializing the request.
+func s3Metadata(userMeta map[string]string) map[string]string {
+    metadata := make(map[string]string, len(userMeta))
+    for k, v := range userMeta {
+        metadata[strings.TrimPrefix(strings.ToLower(k), strings.ToLower(userMetaPrefix))] = v
+    }
+    return metadata
+}
+
+// userMetaFromS3 restores the SDK's bare, lowercased Metadata keys to the
+// "X-Amz-Meta-<Name>" form ObjectInfo.UserMeta and writeObjectHeaders expect.
+func userMetaFromS3(metadata map[string]string) map[string]string {
+    if len(metadata) == 0 {
+        return nil
+    }
+    userMeta := make(map[string]string, len(metadata))
+    for k, v := range metadata {
+        userMeta[http.CanonicalHeaderKey(userMetaPrefix+k)] = v
+    }
+    return userMeta
+}
+
+// isS3NotFound reports whether err is the smithy API error S3 returns for a
+// missing key - HeadObject/GetObject return distinct types for the same
+// condition, neither of which os.IsNotExist recognizes.
+func isS3NotFound(err error) bool {
+    var notFound *types.NotFound
+    if errors.As(err, &notFound) {
+        return true
+    }
+    var noSuchKey *types.NoSuchKey
+    return errors.As(err, &noSuchKey)
+}
+
+// S3Backend proxies object operations to a real AWS S3 or MinIO endpoint,
+// letting mini-s3 run as a thin forwarding layer in front of another
+// store instead of keeping objects on local disk. Nothing is cached
+// locally; every operation goes to the upstream endpoint.
+type S3Backend struct {
+    client *s3.Client
+}
+
+// NewS3Backend builds an S3Backend. endpoint may be empty to use AWS S3
+// directly, or point at a MinIO-compatible endpoint for local testing.
+func NewS3Backend(ctx context.Context, endpoint string, usePathStyle bool) (*S3Backend, error) {
+    cfg, err := config.LoadDefaultConfig(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("loading aws config: %w", err)
+    }
+
+    client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+        if endpoint != "" {
+            o.BaseEndpoint = aws.String(endpoint)
+        }
+        o.UsePathStyle = usePathStyle
+    })
+
+    return &S3Backend{client: client}, nil
+}
+
+func (b *S3Backend) Put(ctx context.Context, bucket, key string, r io.Reader, info ObjectInfo) (ObjectInfo, error) {
+    out, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+        Bucket:      aws.String(bucket),
+        Key:         aws.String(key),
+        Body:        r,
+        ContentType: aws.String(info.ContentType),
+        Metadata:    s3Metadata(info.UserMeta),
+    })
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+
+    head, err := b.Head(ctx, bucket, key)
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+    if out.ETag != nil {
+        head.ETag = unquoteETag(*out.ETag)
+    }
+    return head, nil
+}
+
+func (b *S3Backend) Get(ctx context.Context, bucket, key string) (io.ReadCloser, ObjectInfo, error) {
+    out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+        Bucket: aws.String(bucket),
+        Key:    aws.String(key),
+    })
+    if err != nil {
+        if isS3NotFound(err) {
+            return nil, ObjectInfo{}, ErrNotFound
+        }
+        return nil, ObjectInfo{}, err
+    }
+
+    info := objectInfoFromGet(key, out.ContentLength, out.ETag, out.LastModified, out.ContentType, out.Metadata)
+    return out.Body, info, nil
+}
+
+func (b *S3Backend) Head(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+    out, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+        Bucket: aws.String(bucket),
+        Key:    aws.String(key),
+    })
+    if err != nil {
+        if isS3NotFound(err) {
+            return ObjectInfo{}, ErrNotFound
+        }
+        return ObjectInfo{}, err
+    }
+
+    return objectInfoFromGet(key, out.ContentLength, out.ETag, out.LastModified, out.ContentType, out.Metadata), nil
+}
+
+// Delete returns DeleteObject's error as is; it never returns ErrNotFound,
+// so deleting a missing key reports success.
+func (b *S3Backend) Delete(ctx context.Context, bucket, key string) error {
+    _, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+        Bucket: aws.String(bucket),
+        Key:    aws.String(key),
+    })
+    return err
+}
+
+func (b *S3Backend) List(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+    var objects []ObjectInfo
+
+    paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+        Bucket: aws.String(bucket),
+        Prefix: aws.String(prefix),
+    })
+    for paginator.HasMorePages() {
+        page, err := paginator.NextPage(ctx)
+        if err != nil {
+            return nil, err
+        }
+        for _, obj := range page.Contents {
+            objects = append(objects, ObjectInfo{
+                Key:          aws.ToString(obj.Key),
+                Size:         aws.ToInt64(obj.Size),
+                ETag:         unquoteETag(aws.ToString(obj.ETag)),
+                LastModified: aws.ToTime(obj.LastModified),
+            })
+        }
+    }
+
+    return objects, nil
+}
+
+// GetRange implements RangeReader, translating a byte range through to an
+// S3 Range request rather than downloading the whole object.
+func (b *S3Backend) GetRange(ctx context.Context, bucket, key string, start, end int64) (io.ReadCloser, error) {
+    out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+        Bucket: aws.String(bucket),
+        Key:    aws.String(key),
+        Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+    })
+    if err != nil {
+        if isS3NotFound(err) {
+            return nil, ErrNotFound
+        }
+        return nil, err
+    }
+    return out.Body, nil
+}
+
+func objectInfoFromGet(key string, size *int64, etag *string, lastModified *time.Time, contentType *string, metadata map[string]string) ObjectInfo {
+    info := ObjectInfo{
+        Key:          key,
+        ETag:         unquoteETag(aws.ToString(etag)),
+        ContentType:  aws.ToString(contentType),
+        UserMeta:     userMetaFromS3(metadata),
+        LastModified: aws.ToTime(lastModified),
+    }
+    if size != nil {
+        info.Size = *size
+    }
+    return info
+}
+
+func unquoteETag(etag string) string {
+    if len(etag) >= 2 && etag[0] == '"' && etag[len(etag)-1] == '"' {
+        return etag[1 : len(etag)-1]
+    }
+    return etag
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestS3MetadataStripsPrefix(t *testing.T) {
+    got := s3Metadata(map[string]string{"X-Amz-Meta-Foo": "bar", "X-Amz-Meta-Baz-Qux": "quux"})
+    want := map[string]string{"foo": "bar", "baz-qux": "quux"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("s3Metadata = %#v, want %#v", got, want)
+    }
+}
+
+func TestUserMetaFromS3RestoresPrefix(t *testing.T) {
+    got := userMetaFromS3(map[string]string{"foo": "bar", "baz-qux": "quux"})
+    want := map[string]string{"X-Amz-Meta-Foo": "bar", "X-Amz-Meta-Baz-Qux": "quux"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("userMetaFromS3 = %#v, want %#v", got, want)
+    }
+}
+
+func TestUserMetaFromS3Empty(t *testing.T) {
+    if got := userMetaFromS3(nil); got != nil {
+        t.Errorf("userMetaFromS3(nil) = %#v, want nil", got)
+    }
+}
+
+// TestUserMetaRoundTrip confirms a header key set by handlePut survives a
+// Put -> Get/Head round trip through the SDK's bare-key Metadata
+// representation unchanged.
+func TestUserMetaRoundTrip(t *testing.T) {
+    original := map[string]string{"X-Amz-Meta-Owner-Team": "payments"}
+    roundTripped := userMetaFromS3(s3Metadata(original))
+    if !reflect.DeepEqual(roundTripped, original) {
+        t.Errorf("round trip = %#v, want %#v", roundTripped, original)
+    }
+}
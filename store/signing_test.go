@@ -0,0 +1,80 @@
+package store
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestVerifyRequestValidSignature(t *testing.T) {
+    fs := &FileStore{Keys: KeyStore{"k1": "secret"}}
+    url := PresignURL("http://example.com", "k1", "secret", http.MethodGet, "/bucket/key", "", time.Minute)
+
+    r := httptest.NewRequest(http.MethodGet, mustPath(url), nil)
+    if status, _ := fs.verifyRequest(r); status != 0 {
+        t.Fatalf("verifyRequest = %d, want 0", status)
+    }
+}
+
+func TestVerifyRequestExpired(t *testing.T) {
+    fs := &FileStore{Keys: KeyStore{"k1": "secret"}}
+    url := PresignURL("http://example.com", "k1", "secret", http.MethodGet, "/bucket/key", "", -time.Minute)
+
+    r := httptest.NewRequest(http.MethodGet, mustPath(url), nil)
+    if status, _ := fs.verifyRequest(r); status != http.StatusForbidden {
+        t.Fatalf("verifyRequest = %d, want %d", status, http.StatusForbidden)
+    }
+}
+
+func TestVerifyRequestWrongSecret(t *testing.T) {
+    fs := &FileStore{Keys: KeyStore{"k1": "other-secret"}}
+    url := PresignURL("http://example.com", "k1", "secret", http.MethodGet, "/bucket/key", "", time.Minute)
+
+    r := httptest.NewRequest(http.MethodGet, mustPath(url), nil)
+    if status, _ := fs.verifyRequest(r); status != http.StatusUnauthorized {
+        t.Fatalf("verifyRequest = %d, want %d", status, http.StatusUnauthorized)
+    }
+}
+
+func TestVerifyRequestQueryTamperRejected(t *testing.T) {
+    fs := &FileStore{Keys: KeyStore{"k1": "secret"}}
+    url := PresignURL("http://example.com", "k1", "secret", http.MethodGet, "/bucket/", "list-type=2", time.Minute)
+
+    r := httptest.NewRequest(http.MethodGet, mustPath(url), nil)
+    q := r.URL.Query()
+    q.Set("archive", "zip")
+    q.Del("list-type")
+    r.URL.RawQuery = q.Encode()
+
+    if status, _ := fs.verifyRequest(r); status != http.StatusUnauthorized {
+        t.Fatalf("verifyRequest with swapped query = %d, want %d", status, http.StatusUnauthorized)
+    }
+}
+
+func TestVerifyRequestMalformedAuthHeader(t *testing.T) {
+    fs := &FileStore{Keys: KeyStore{"k1": "secret"}}
+    r := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+    r.Header.Set("Authorization", "HMAC not-enough-parts")
+
+    if status, _ := fs.verifyRequest(r); status != http.StatusUnauthorized {
+        t.Fatalf("verifyRequest with malformed header = %d, want %d", status, http.StatusUnauthorized)
+    }
+}
+
+func TestVerifyRequestNoKeysConfigured(t *testing.T) {
+    fs := &FileStore{}
+    r := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+
+    if status, hash := fs.verifyRequest(r); status != 0 || hash != "" {
+        t.Fatalf("verifyRequest with no keys = (%d, %q), want (0, \"\")", status, hash)
+    }
+}
+
+// mustPath strips the scheme+host off a PresignURL result so it can be
+// passed to httptest.NewRequest, which wants a path (+ query), not an
+// absolute URL.
+func mustPath(rawURL string) string {
+    const prefix = "http://example.com"
+    return rawURL[len(prefix):]
+}
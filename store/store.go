@@ -1,27 +1,85 @@
 package store
 
 import (
+    "context"
     "crypto/sha256"
     "encoding/hex"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "net/http"
-    "os"
     "path/filepath"
     "strconv"
     "strings"
+    "sync"
+    "time"
 )
 
 type FileStore struct {
     root string
+
+    // RequireDeleteKey gates DELETE on the delete_key recorded in an
+    // object's metadata sidecar. Defaults to true; set false to disable.
+    RequireDeleteKey bool
+
+    // backend serves Put/Get/Head/Delete/List for the HTTP handlers.
+    // Defaults to the FileStore's own filesystem implementation; set via
+    // NewWithBackend to proxy to a different store such as S3Backend.
+    backend Backend
+
+    // local is true when backend is the FileStore's own filesystem
+    // implementation. Capabilities that only make sense against local
+    // disk - multipart upload staging and archive streaming - are
+    // restricted to that case.
+    local bool
+
+    // etagCache short-circuits the xattr/sidecar digest lookup for objects
+    // with no metadata sidecar at all (e.g. dropped onto disk out of band).
+    etagCache *lruCache
+
+    // metaCache short-circuits the metadata sidecar read on repeat GET/HEAD
+    // of the same object - the actual hot path for the local filesystem
+    // backend, since every Put (and multipart Complete) writes a sidecar.
+    metaCache *lruCache
+
+    // Keys gates PUT/GET/DELETE on a valid HMAC signature (header or
+    // presigned query string) when non-empty. Nil disables auth entirely.
+    Keys KeyStore
+
+    // uploadLocks holds one *sync.Mutex per in-progress uploadID, guarding
+    // the load-mutate-save sequence against concurrent part uploads.
+    uploadLocks sync.Map
 }
 
+// metaCacheSize is the number of (path, mtime, size) -> cached value
+// entries kept in memory, per cache, before the LRU evicts the oldest.
+const metaCacheSize = 4096
+
 func NewFileStore(root string) *FileStore {
-    return &FileStore{root: root}
+    fs := &FileStore{root: root, RequireDeleteKey: true, local: true, etagCache: newLRUCache(metaCacheSize), metaCache: newLRUCache(metaCacheSize)}
+    fs.backend = fs
+    return fs
+}
+
+// NewWithBackend builds a FileStore whose basic object operations are
+// served by backend instead of the local filesystem. Multipart uploads and
+// archive streaming are answered with 501 Not Implemented, since local is
+// false, and DELETE does not check a delete key.
+func NewWithBackend(root string, backend Backend) *FileStore {
+    return &FileStore{root: root, RequireDeleteKey: true, backend: backend, local: false, etagCache: newLRUCache(metaCacheSize), metaCache: newLRUCache(metaCacheSize)}
 }
 
 func (fs *FileStore) Handler(w http.ResponseWriter, r *http.Request) {
+    status, boundHash := fs.verifyRequest(r)
+    if status != 0 {
+        http.Error(w, http.StatusText(status), status)
+        return
+    }
+    if boundHash != "" {
+        r = r.WithContext(context.WithValue(r.Context(), boundBodySHA256Key, boundHash))
+    }
+
     switch r.Method {
     case http.MethodPut:
         fs.handlePut(w, r)
@@ -29,6 +87,8 @@ func (fs *FileStore) Handler(w http.ResponseWriter, r *http.Request) {
         fs.handleGet(w, r)
     case http.MethodHead:
         fs.handleHead(w, r)
+    case http.MethodPost:
+        fs.handlePost(w, r)
     case http.MethodDelete:
         fs.handleDelete(w, r)
     default:
@@ -36,6 +96,33 @@ func (fs *FileStore) Handler(w http.ResponseWriter, r *http.Request) {
     }
 }
 
+// handlePost dispatches the multipart upload initiate and complete actions,
+// which both use POST and are distinguished by their query string.
+func (fs *FileStore) handlePost(w http.ResponseWriter, r *http.Request) {
+    if !fs.local {
+        http.Error(w, "multipart upload is not supported by this backend", http.StatusNotImplemented)
+        return
+    }
+
+    bucket, key, err := fs.parsePath(r.URL.Path)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    q := r.URL.Query()
+    if _, ok := q["uploads"]; ok {
+        fs.handleInitiateMultipart(w, r, bucket, key)
+        return
+    }
+    if uploadID := q.Get("uploadId"); uploadID != "" {
+        fs.handleCompleteMultipart(w, r, bucket, key, uploadID)
+        return
+    }
+
+    http.Error(w, "unsupported query", http.StatusBadRequest)
+}
+
 func (fs *FileStore) fullPath(bucket, key string) string {
     return filepath.Join(fs.root, bucket, key)
 }
@@ -47,77 +134,119 @@ func (fs *FileStore) handlePut(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    path := fs.fullPath(bucket, key)
-    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-        http.Error(w, "failed to create directory", http.StatusInternalServerError)
+    q := r.URL.Query()
+    if uploadID := q.Get("uploadId"); uploadID != "" {
+        partNumber, err := strconv.Atoi(q.Get("partNumber"))
+        if err != nil || partNumber < 1 {
+            http.Error(w, "invalid partNumber", http.StatusBadRequest)
+            return
+        }
+        fs.handleUploadPart(w, r, bucket, key, uploadID, partNumber)
         return
     }
 
-    file, err := os.Create(path)
-    if err != nil {
-        http.Error(w, "failed to create file", http.StatusInternalServerError)
-        return
+    userMeta := map[string]string{}
+    for k, v := range r.Header {
+        if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") {
+            userMeta[k] = v[0]
+        }
     }
-    defer file.Close()
 
+    put := ObjectInfo{
+        ContentType: r.Header.Get("Content-Type"),
+        UserMeta:    userMeta,
+    }
+    if raw := r.Header.Get("X-Expire-Seconds"); raw != "" {
+        if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil && seconds > 0 {
+            put.Expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+        }
+    }
+
+    // A signed request's HMAC covers a claimed X-Content-Sha256, but that
+    // claim is worthless unless it's checked against the bytes actually
+    // written. Hash the body as it streams to the backend, independent of
+    // whatever ETag scheme that backend happens to use.
+    boundHash := boundBodySHA256(r)
+    var body io.Reader = r.Body
     hasher := sha256.New()
-    mw := io.MultiWriter(file, hasher)
+    if boundHash != "" {
+        body = io.TeeReader(r.Body, hasher)
+    }
 
-    if _, err := io.Copy(mw, r.Body); err != nil {
-        os.Remove(path) // cleanup on error
-        http.Error(w, "failed to write file", http.StatusInternalServerError)
+    info, err := fs.backend.Put(r.Context(), bucket, key, body, put)
+    if err != nil {
+        http.Error(w, "failed to write object", http.StatusInternalServerError)
+        return
+    }
+
+    if boundHash != "" && boundHash != hex.EncodeToString(hasher.Sum(nil)) {
+        fs.backend.Delete(r.Context(), bucket, key)
+        http.Error(w, "body does not match signed content hash", http.StatusBadRequest)
         return
     }
 
-    etag := hex.EncodeToString(hasher.Sum(nil))
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(map[string]string{"etag": etag})
+    json.NewEncoder(w).Encode(map[string]string{"etag": info.ETag})
 }
 
 func (fs *FileStore) handleGet(w http.ResponseWriter, r *http.Request) {
-    bucket, key, err := fs.parsePath(r.URL.Path)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
+    if format := r.URL.Query().Get("archive"); format != "" {
+        if !fs.local {
+            http.Error(w, "archive download is not supported by this backend", http.StatusNotImplemented)
+            return
+        }
+        bucket := strings.Trim(r.URL.Path, "/")
+        fs.handleArchive(w, r, bucket, format, r.URL.Query().Get("prefix"))
         return
     }
 
-    path := fs.fullPath(bucket, key)
-    file, err := os.Open(path)
-    if err != nil {
-        if os.IsNotExist(err) {
-            http.NotFound(w, r)
-        } else {
-            http.Error(w, "failed to open file", http.StatusInternalServerError)
-        }
+    if r.URL.Query().Get("list-type") == "2" {
+        bucket := strings.Trim(r.URL.Path, "/")
+        fs.handleListObjects(w, r, bucket)
         return
     }
-    defer file.Close()
 
-    stat, err := file.Stat()
+    bucket, key, err := fs.parsePath(r.URL.Path)
     if err != nil {
-        http.Error(w, "failed to stat file", http.StatusInternalServerError)
+        http.Error(w, err.Error(), http.StatusBadRequest)
         return
     }
 
-    // Set headers
-    w.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
-    w.Header().Set("Last-Modified", stat.ModTime().UTC().Format(http.TimeFormat))
-    
-    // Calculate ETag if needed
-    if etag := fs.calculateETag(file); etag != "" {
-        w.Header().Set("ETag", `"`+etag+`"`)
+    // A Range request needs the object's size before it can call GetRange,
+    // and shouldn't pay for opening the full body just to throw it away, so
+    // it stats first. Everything else reads the object's ObjectInfo off of
+    // Get directly instead of also calling Head - Get already returns it,
+    // and for backends like S3 a separate Head is a whole extra API round trip.
+    if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+        info, err := fs.backend.Head(r.Context(), bucket, key)
+        if err != nil {
+            if errors.Is(err, ErrNotFound) {
+                http.NotFound(w, r)
+            } else {
+                http.Error(w, "failed to stat object", http.StatusInternalServerError)
+            }
+            return
+        }
+        writeObjectHeaders(w, info)
+        fs.handleRangeRequest(w, r, bucket, key, info.Size, rangeHeader)
+        return
     }
 
-    // Handle range requests
-    if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
-        fs.handleRangeRequest(w, r, file, stat.Size(), rangeHeader)
+    body, info, err := fs.backend.Get(r.Context(), bucket, key)
+    if err != nil {
+        if errors.Is(err, ErrNotFound) {
+            http.NotFound(w, r)
+        } else {
+            http.Error(w, "failed to open object", http.StatusInternalServerError)
+        }
         return
     }
+    defer body.Close()
 
-    // Reset file position after ETag calculation
-    file.Seek(0, 0)
-    http.ServeContent(w, r, key, stat.ModTime(), file)
+    writeObjectHeaders(w, info)
+    w.WriteHeader(http.StatusOK)
+    io.Copy(w, body)
 }
 
 func (fs *FileStore) handleHead(w http.ResponseWriter, r *http.Request) {
@@ -127,33 +256,34 @@ func (fs *FileStore) handleHead(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    path := fs.fullPath(bucket, key)
-    file, err := os.Open(path)
+    info, err := fs.backend.Head(r.Context(), bucket, key)
     if err != nil {
-        if os.IsNotExist(err) {
+        if errors.Is(err, ErrNotFound) {
             http.NotFound(w, r)
         } else {
-            http.Error(w, "failed to open file", http.StatusInternalServerError)
+            http.Error(w, "failed to stat object", http.StatusInternalServerError)
         }
         return
     }
-    defer file.Close()
 
-    stat, err := file.Stat()
-    if err != nil {
-        http.Error(w, "failed to stat file", http.StatusInternalServerError)
-        return
-    }
+    writeObjectHeaders(w, info)
+    w.WriteHeader(http.StatusOK)
+}
 
-    // Set same headers as GET but no body
-    w.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
-    w.Header().Set("Last-Modified", stat.ModTime().UTC().Format(http.TimeFormat))
-    
-    if etag := fs.calculateETag(file); etag != "" {
-        w.Header().Set("ETag", `"`+etag+`"`)
+func writeObjectHeaders(w http.ResponseWriter, info ObjectInfo) {
+    w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+    if !info.LastModified.IsZero() {
+        w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+    }
+    if info.ContentType != "" {
+        w.Header().Set("Content-Type", info.ContentType)
+    }
+    for k, v := range info.UserMeta {
+        w.Header().Set(k, v)
+    }
+    if info.ETag != "" {
+        w.Header().Set("ETag", `"`+info.ETag+`"`)
     }
-
-    w.WriteHeader(http.StatusOK)
 }
 
 func (fs *FileStore) handleDelete(w http.ResponseWriter, r *http.Request) {
@@ -163,12 +293,28 @@ func (fs *FileStore) handleDelete(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    path := fs.fullPath(bucket, key)
-    if err := os.Remove(path); err != nil {
-        if os.IsNotExist(err) {
+    if uploadID := r.URL.Query().Get("uploadId"); uploadID != "" {
+        if !fs.local {
+            http.Error(w, "multipart upload is not supported by this backend", http.StatusNotImplemented)
+            return
+        }
+        fs.handleAbortMultipart(w, r, bucket, key, uploadID)
+        return
+    }
+
+    if fs.local {
+        meta, err := fs.loadMeta(fs.fullPath(bucket, key))
+        if err == nil && !fs.checkDeleteKey(r, meta) {
+            http.Error(w, "invalid delete key", http.StatusForbidden)
+            return
+        }
+    }
+
+    if err := fs.backend.Delete(r.Context(), bucket, key); err != nil {
+        if errors.Is(err, ErrNotFound) {
             http.NotFound(w, r)
         } else {
-            http.Error(w, "failed to delete file", http.StatusInternalServerError)
+            http.Error(w, "failed to delete object", http.StatusInternalServerError)
         }
         return
     }
@@ -198,20 +344,7 @@ func (fs *FileStore) parsePath(path string) (bucket, key string, err error) {
     return bucket, key, nil
 }
 
-func (fs *FileStore) calculateETag(file *os.File) string {
-    hasher := sha256.New()
-    currentPos, _ := file.Seek(0, 1) // save current position
-    file.Seek(0, 0)                 // go to start
-    
-    if _, err := io.Copy(hasher, file); err != nil {
-        return ""
-    }
-    
-    file.Seek(currentPos, 0) // restore position
-    return hex.EncodeToString(hasher.Sum(nil))
-}
-
-func (fs *FileStore) handleRangeRequest(w http.ResponseWriter, r *http.Request, file *os.File, fileSize int64, rangeHeader string) {
+func (fs *FileStore) handleRangeRequest(w http.ResponseWriter, r *http.Request, bucket, key string, fileSize int64, rangeHeader string) {
     // Parse Range header: "bytes=start-end"
     if !strings.HasPrefix(rangeHeader, "bytes=") {
         http.Error(w, "invalid range header", http.StatusBadRequest)
@@ -257,11 +390,23 @@ func (fs *FileStore) handleRangeRequest(w http.ResponseWriter, r *http.Request,
     }
 
     contentLength := end - start + 1
-    
+
+    rr, ok := fs.backend.(RangeReader)
+    if !ok {
+        http.Error(w, "range requests not supported by this backend", http.StatusNotImplemented)
+        return
+    }
+
+    body, err := rr.GetRange(r.Context(), bucket, key, start, end)
+    if err != nil {
+        http.Error(w, "failed to read range", http.StatusInternalServerError)
+        return
+    }
+    defer body.Close()
+
     w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, fileSize))
     w.Header().Set("Content-Length", strconv.FormatInt(contentLength, 10))
     w.WriteHeader(http.StatusPartialContent)
 
-    file.Seek(start, 0)
-    io.CopyN(w, file, contentLength)
+    io.CopyN(w, body, contentLength)
 }
\ No newline at end of file